@@ -11,26 +11,33 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"net/http"
-	"net/url"
 	"os"
-	"path"
 	"strings"
+	"sync"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/agrison/go-tablib"
 	"github.com/genuinetools/pkg/cli"
 	"github.com/sirupsen/logrus"
-
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend/alpine"
+	_ "github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend/arch"
+	_ "github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend/debian"
+	_ "github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend/fedora"
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/lddtree"
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/repology"
 	"github.com/sniperkit/snk.fork.genuinetools-apk-file/version"
 )
 
-const (
-	alpineContentsSearchURI = "https://pkgs.alpinelinux.org/contents"
-)
+// requestsPerSecond bounds how fast batch stdin queries hit
+// pkgs.alpinelinux.org (or another backend's upstream), regardless of
+// -concurrency.
+const requestsPerSecond = 5
 
 type fileInfo struct {
-	path, pkg, branch, repo, arch string
+	query, sourcePkg, path, pkg, branch, repo, arch, distro string
 }
 
 var (
@@ -38,6 +45,7 @@ var (
 )
 
 var (
+	distro string
 	arch   string
 	branch string
 	repo   string
@@ -51,19 +59,28 @@ var (
 	outputFormat   string
 	outputType     string
 
-	tree  bool
-	debug bool
-	stdin bool
+	tree    bool
+	debug   bool
+	stdin   bool
+	offline bool
+
+	mapFrom string
+	mapOnly bool
+
+	concurrency   int
+	failFast      bool
+	continueOnErr bool
+
+	lddtreeMode bool
+	sysroot     string
+
+	selectedBackend backend.Backend
 
 	validWildcards   = []string{"*", "?"}
 	validFilterTypes = []string{"file", "path", "package"}
 
 	validOutputTypes   = []string{"stdout", "file"}
 	validOutputFormats = []string{"markdown", "csv", "yaml", "json", "xlsx", "xml", "tsv", "mysql", "postgres", "html", "ascii"}
-
-	validArches   = []string{"x86", "x86_64", "armhf", "aarch64", "ppc64le", "s390x"}
-	validRepos    = []string{"main", "community", "testing"}
-	validBranches = []string{"edge", "v3.8", "v3.7", "v3.6", "v3.5", "v3.4", "v3.3"}
 )
 
 func main() {
@@ -76,6 +93,11 @@ func main() {
 	p.GitCommit = version.GITCOMMIT
 	p.Version = version.VERSION
 
+	// Add the serve subcommand.
+	p.Commands = []cli.Command{
+		&serveCommand{},
+	}
+
 	// Setup the global flags.
 	p.FlagSet = flag.NewFlagSet("global", flag.ExitOnError)
 
@@ -87,9 +109,10 @@ func main() {
 	// - inside docker container
 	//   - for converting a list of packages from apt, yum, pacman or many others ... (nb. with the mapping provided by repology api v1)
 	//   - for build or lddtree linked shared libraries
-	p.FlagSet.StringVar(&branch, "branch", "v3.8", "alpine branch ("+strings.Join(validBranches, ", ")+")")
-	p.FlagSet.StringVar(&repo, "repo", "main", "repository to search in ("+strings.Join(validRepos, ", ")+")")
-	p.FlagSet.StringVar(&arch, "arch", "x86_64", "arch to search for ("+strings.Join(validArches, ", ")+")")
+	p.FlagSet.StringVar(&distro, "distro", "alpine", "distro to search ("+strings.Join(backend.Names(), ", ")+")")
+	p.FlagSet.StringVar(&branch, "branch", "v3.8", "distro branch/suite to search")
+	p.FlagSet.StringVar(&repo, "repo", "main", "repository/component to search in")
+	p.FlagSet.StringVar(&arch, "arch", "x86_64", "arch to search for")
 
 	p.FlagSet.StringVar(&filterType, "filter", "", "pattern filter ("+strings.Join(validFilterTypes, ", ")+")")
 
@@ -101,6 +124,17 @@ func main() {
 	p.FlagSet.BoolVar(&stdin, "stdin", false, "enable stdin mode")
 	p.FlagSet.BoolVar(&tree, "tree", false, "enable tree mode")
 	p.FlagSet.BoolVar(&debug, "debug", false, "enable debug logging")
+	p.FlagSet.BoolVar(&offline, "offline", false, "search a locally cached APKINDEX instead of scraping pkgs.alpinelinux.org (alpine only)")
+
+	p.FlagSet.StringVar(&mapFrom, "map-from", "", "resolve the query as a package name known to this repology repo (e.g. debian_12), then search for the mapped -distro package(s)")
+	p.FlagSet.BoolVar(&mapOnly, "map-only", false, "with -map-from, only print the repology name mapping without searching -distro")
+
+	p.FlagSet.IntVar(&concurrency, "concurrency", 8, "number of stdin queries to run concurrently")
+	p.FlagSet.BoolVar(&failFast, "fail-fast", false, "with -stdin, abort all in-flight queries as soon as one fails")
+	p.FlagSet.BoolVar(&continueOnErr, "continue-on-error", false, "with -stdin, log failed queries but keep processing the rest (default)")
+
+	p.FlagSet.BoolVar(&lddtreeMode, "lddtree", false, "walk the shared-library dependency tree of the given ELF binary or package name and resolve it to owning packages")
+	p.FlagSet.StringVar(&sysroot, "sysroot", "/", "root filesystem to resolve RPATH/RUNPATH entries and shared libraries against")
 
 	// Set the before function.
 	p.Before = func(ctx context.Context) error {
@@ -117,16 +151,54 @@ func main() {
 			return fmt.Errorf("%s is not a valid pattern type allowed: "+strings.Join(validFilterTypes, ", "), filterType)
 		}
 
-		if branch != "" && !stringInSlice(branch, validBranches) {
-			return fmt.Errorf("%s is not a valid version, allowed: "+strings.Join(validBranches, ", "), branch)
+		b, err := backend.Get(distro)
+		if err != nil {
+			return err
+		}
+		if alpineBackend, ok := b.(*alpine.Backend); ok {
+			alpineBackend.Offline = offline
+		}
+		selectedBackend = b
+
+		// branch/repo/arch default to Alpine's conventions (v3.8/main/x86_64),
+		// which aren't valid for every backend. If the user didn't explicitly
+		// pass one of these flags, fall back to the selected backend's own
+		// default instead of failing validation below.
+		explicit := map[string]bool{}
+		p.FlagSet.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+		if !explicit["branch"] && len(b.ValidBranches()) > 0 && !stringInSlice(branch, b.ValidBranches()) {
+			branch = b.ValidBranches()[0]
+		}
+		if !explicit["repo"] && len(b.ValidRepos()) > 0 && !stringInSlice(repo, b.ValidRepos()) {
+			repo = b.ValidRepos()[0]
+		}
+		if !explicit["arch"] && len(b.ValidArches()) > 0 && !stringInSlice(arch, b.ValidArches()) {
+			arch = b.ValidArches()[0]
+		}
+
+		if branch != "" && len(b.ValidBranches()) > 0 && !stringInSlice(branch, b.ValidBranches()) {
+			return fmt.Errorf("%s is not a valid branch for %s, allowed: "+strings.Join(b.ValidBranches(), ", "), branch, distro)
+		}
+
+		if arch != "" && len(b.ValidArches()) > 0 && !stringInSlice(arch, b.ValidArches()) {
+			return fmt.Errorf("%s is not a valid arch for %s, allowed: "+strings.Join(b.ValidArches(), ", "), arch, distro)
+		}
+
+		if repo != "" && len(b.ValidRepos()) > 0 && !stringInSlice(repo, b.ValidRepos()) {
+			return fmt.Errorf("%s is not a valid repo for %s, allowed: "+strings.Join(b.ValidRepos(), ", "), repo, distro)
+		}
+
+		if mapOnly && mapFrom == "" {
+			return errors.New("-map-only requires -map-from to be set")
 		}
 
-		if arch != "" && !stringInSlice(arch, validArches) {
-			return fmt.Errorf("%s is not a valid arch, allowed: "+strings.Join(validArches, ", "), arch)
+		if failFast && continueOnErr {
+			return errors.New("-fail-fast and -continue-on-error are mutually exclusive")
 		}
 
-		if repo != "" && !stringInSlice(repo, validRepos) {
-			return fmt.Errorf("%s is not a valid repo, allowed: "+strings.Join(validRepos, ", "), repo)
+		if concurrency < 1 {
+			return fmt.Errorf("-concurrency must be at least 1, got %d", concurrency)
 		}
 
 		return nil
@@ -138,60 +210,51 @@ func main() {
 
 		// pp.Println("args: ", args)
 
+		if lddtreeMode {
+			return runLddtree(ctx, p)
+		}
+
 		// nb. check if input is a string or a stdin
 
 		var input string
+		var files []fileInfo
+		var err error
 		if stdin {
-			if ok, err := checkStdin(); ok {
-				input = readStdin()
-				input = strings.TrimSuffix(input, "\n")
-			} else {
-				return fmt.Errorf("stdin is invalid, msg: %s", err)
+			if ok, serr := checkStdin(); !ok {
+				return fmt.Errorf("stdin is invalid, msg: %s", serr)
 			}
+			input = readStdin()
+			logrus.Infoln("args: ", args)
+			logrus.Infoln("wildcard: ", wildcard)
+			logrus.Infoln("branch: ", branch)
+
+			files, err = runBatch(ctx, strings.Split(input, "\n"))
 		} else {
 			if p.FlagSet.NArg() < 1 {
 				return errors.New("must pass a file to search for")
 			}
 			input = p.FlagSet.Arg(0)
-		}
-
-		input = fmt.Sprintf("%s%s", input, wildcard)
-
-		logrus.Infoln("args: ", args)
-		logrus.Infoln("input: ", input)
-		logrus.Infoln("wildcard: ", wildcard)
-		logrus.Infoln("branch: ", branch)
 
-		// todo: a foreach for multiple patterns matching
-		f, p := getFileAndPath(input)
+			logrus.Infoln("args: ", args)
+			logrus.Infoln("input: ", input)
+			logrus.Infoln("wildcard: ", wildcard)
+			logrus.Infoln("branch: ", branch)
 
-		query := url.Values{
-			"file":   {f},
-			"path":   {p},
-			"branch": {branch},
-			"repo":   {repo},
-			"arch":   {arch},
-		}
-
-		uri := fmt.Sprintf("%s?%s", alpineContentsSearchURI, query.Encode())
-		logrus.Debugf("requesting from %s", uri)
-		resp, err := http.Get(uri)
-		if err != nil {
-			logrus.Fatalf("requesting %s failed: %v", uri, err)
-			// return err
+			if mapFrom != "" {
+				files, err = searchMapped(ctx, input)
+			} else {
+				input = fmt.Sprintf("%s%s", input, wildcard)
+				files, err = search(ctx, "", input, filterType)
+			}
 		}
-		defer resp.Body.Close()
-		doc, err := goquery.NewDocumentFromReader(resp.Body)
 		if err != nil {
-			logrus.Fatalf("creating document failed: %v", err)
-			// return err
+			return err
 		}
 
-		files := getFilesInfo(doc)
-		contentDataset := tablib.NewDataset([]string{"file", "package", "branch", "repository", "architecture"})
+		contentDataset := tablib.NewDataset([]string{"query", "source_pkg", "file", "package", "branch", "repository", "architecture", "distro"})
 
 		for _, f := range files {
-			contentDataset.AppendValues(f.path, f.pkg, f.branch, f.repo, f.arch)
+			contentDataset.AppendValues(f.query, f.sourcePkg, f.path, f.pkg, f.branch, f.repo, f.arch, f.distro)
 		}
 
 		if _, err := tabularResults(contentDataset); err != nil {
@@ -205,6 +268,182 @@ func main() {
 	p.Run()
 }
 
+// runLddtree resolves the transitive shared-library closure of the binary
+// (or package) passed as the command's sole argument and prints it through
+// the usual tabular pipeline.
+func runLddtree(ctx context.Context, p *cli.Program) error {
+	if p.FlagSet.NArg() < 1 {
+		return errors.New("must pass a path to an ELF binary or a package name")
+	}
+	binaryPath := p.FlagSet.Arg(0)
+
+	q := backend.Query{
+		Branch: branch,
+		Repo:   repo,
+		Arch:   arch,
+	}
+
+	// If the argument isn't a file we can open directly, treat it as a
+	// package name and resolve it to one of its on-disk binaries first.
+	if info, statErr := os.Stat(binaryPath); statErr != nil || info.IsDir() {
+		resolved, err := lddtree.FindPackageBinary(ctx, selectedBackend, binaryPath, sysroot, q)
+		if err != nil {
+			return fmt.Errorf("%s is not a binary path and could not be resolved as a package name: %v", binaryPath, err)
+		}
+		binaryPath = resolved
+	}
+
+	resolutions, err := lddtree.Resolve(ctx, selectedBackend, binaryPath, sysroot, q)
+	if err != nil {
+		return err
+	}
+
+	ds := tablib.NewDataset([]string{"binary", "needed_soname", "resolved_path", "package"})
+	for _, r := range resolutions {
+		ds.AppendValues(r.Binary, r.NeededSoname, r.ResolvedPath, r.Package)
+	}
+
+	_, err = tabularResults(ds)
+	return err
+}
+
+// search runs pattern through the selected backend, tagging every result
+// row with sourcePkg so it can be traced back to the query that produced it.
+func search(ctx context.Context, sourcePkg, pattern, filter string) ([]fileInfo, error) {
+	matches, err := selectedBackend.Search(ctx, backend.Query{
+		Pattern: pattern,
+		Filter:  filter,
+		Branch:  branch,
+		Repo:    repo,
+		Arch:    arch,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]fileInfo, 0, len(matches))
+	for _, m := range matches {
+		files = append(files, fileInfo{
+			sourcePkg: sourcePkg,
+			path:      m.Path,
+			pkg:       m.Package,
+			branch:    m.Branch,
+			repo:      m.Repo,
+			arch:      m.Arch,
+			distro:    m.Distro,
+		})
+	}
+	return files, nil
+}
+
+// searchMapped resolves each non-empty line of input as a package name in
+// mapFrom's convention via Repology, then either records the mapping
+// (-map-only) or searches the selected backend's contents for each mapped
+// package name.
+func searchMapped(ctx context.Context, input string) ([]fileInfo, error) {
+	files := []fileInfo{}
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		names, err := repology.Resolve(line, mapFrom, distro)
+		if err != nil {
+			return nil, fmt.Errorf("mapping %q: %v", line, err)
+		}
+
+		for _, name := range names {
+			if mapOnly {
+				files = append(files, fileInfo{sourcePkg: line, pkg: name, distro: distro})
+				continue
+			}
+
+			matched, err := search(ctx, line, name, "package")
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matched...)
+		}
+	}
+	return files, nil
+}
+
+// runBatch dispatches one query per non-empty line of lines through a
+// bounded worker pool, rate-limiting requests to the upstream backend and
+// merging every line's results into a single slice tagged with the line
+// that produced them. Depending on -fail-fast/-continue-on-error, a
+// per-line failure either cancels every other in-flight query or is logged
+// and skipped.
+func runBatch(ctx context.Context, lines []string) ([]fileInfo, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, concurrency)
+	limiter := rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+
+	var mu sync.Mutex
+	var results []fileInfo
+
+dispatch:
+	for i, line := range lines {
+		line := strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		n := i + 1
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// Stop dispatching new queries, but still wait for the ones
+			// already in flight so their real errors (and not just a
+			// generic "context canceled") surface below.
+			break dispatch
+		}
+
+		g.Go(func() error {
+			defer func() { <-sem }()
+
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+
+			logrus.Debugf("[%d/%d] searching %q", n, len(lines), line)
+
+			var matched []fileInfo
+			var err error
+			if mapFrom != "" {
+				matched, err = searchMapped(ctx, line)
+			} else {
+				matched, err = search(ctx, "", fmt.Sprintf("%s%s", line, wildcard), filterType)
+			}
+
+			if err != nil {
+				if failFast {
+					return fmt.Errorf("query %q failed: %v", line, err)
+				}
+				logrus.Warnf("query %q failed, skipping: %v", line, err)
+				return nil
+			}
+
+			for i := range matched {
+				matched[i].query = line
+			}
+
+			mu.Lock()
+			results = append(results, matched...)
+			mu.Unlock()
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func tabularResults(ds *tablib.Dataset) (result *tablib.Exportable, err error) { // (result *tablib.Dataset, err error) {
 
 	// ds = ds.Sort("package")
@@ -264,32 +503,6 @@ func tabularResults(ds *tablib.Dataset) (result *tablib.Exportable, err error) {
 	return
 }
 
-func getFilesInfo(d *goquery.Document) []fileInfo {
-	files := []fileInfo{}
-	d.Find(".pure-table tr:not(:first-child)").Each(func(j int, l *goquery.Selection) {
-		f := fileInfo{}
-		rows := l.Find("td")
-		rows.Each(func(i int, s *goquery.Selection) {
-			switch i {
-			case 0:
-				f.path = s.Text()
-			case 1:
-				f.pkg = s.Text()
-			case 2:
-				f.branch = s.Text()
-			case 3:
-				f.repo = s.Text()
-			case 4:
-				f.arch = s.Text()
-			default:
-				logrus.Warn("Unmapped value for column %d with value %s", i, s.Text())
-			}
-		})
-		files = append(files, f)
-	})
-	return files
-}
-
 func readStdin() (s string) {
 	if input, err := ioutil.ReadAll(os.Stdin); err != nil {
 		s = err.Error()
@@ -312,18 +525,6 @@ func checkStdin() (bool, error) {
 	return true, nil
 }
 
-func getFileAndPath(arg string) (file string, dir string) {
-	file = "*" + path.Base(arg) + "*"
-	dir = path.Dir(arg)
-	if dir != "" && dir != "." {
-		dir = "*" + dir
-		file = strings.TrimPrefix(file, "*")
-	} else {
-		dir = ""
-	}
-	return file, dir
-}
-
 func stringInSlice(a string, list []string) bool {
 	for _, b := range list {
 		if b == a {
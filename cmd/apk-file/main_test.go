@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+// fakeBackend is a minimal backend.Backend used to exercise runBatch without
+// hitting the network. It tracks the peak number of concurrent Search calls
+// and can be told to fail for specific patterns.
+type fakeBackend struct {
+	mu       sync.Mutex
+	inFlight int32
+	peak     int32
+	failFor  map[string]bool
+}
+
+func (b *fakeBackend) Name() string { return "fake" }
+
+func (b *fakeBackend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	n := atomic.AddInt32(&b.inFlight, 1)
+	defer atomic.AddInt32(&b.inFlight, -1)
+
+	b.mu.Lock()
+	if n > b.peak {
+		b.peak = n
+	}
+	b.mu.Unlock()
+
+	if b.failFor[q.Pattern] {
+		return nil, fmt.Errorf("boom: %s", q.Pattern)
+	}
+	return []backend.FileInfo{{Path: q.Pattern, Package: q.Pattern}}, nil
+}
+
+func (b *fakeBackend) ValidArches() []string   { return nil }
+func (b *fakeBackend) ValidRepos() []string    { return nil }
+func (b *fakeBackend) ValidBranches() []string { return nil }
+
+// withTestGlobals temporarily overrides the package-level flag state runBatch
+// and search depend on, restoring it afterwards.
+func withTestGlobals(t *testing.T, fn func()) {
+	t.Helper()
+
+	origBackend := selectedBackend
+	origConcurrency := concurrency
+	origFailFast := failFast
+	origContinueOnErr := continueOnErr
+	origMapFrom := mapFrom
+	origWildcard := wildcard
+	origFilterType := filterType
+	t.Cleanup(func() {
+		selectedBackend = origBackend
+		concurrency = origConcurrency
+		failFast = origFailFast
+		continueOnErr = origContinueOnErr
+		mapFrom = origMapFrom
+		wildcard = origWildcard
+		filterType = origFilterType
+	})
+
+	fn()
+}
+
+func TestRunBatchRespectsConcurrencyLimit(t *testing.T) {
+	withTestGlobals(t, func() {
+		fb := &fakeBackend{}
+		selectedBackend = fb
+		concurrency = 2
+		mapFrom = ""
+		wildcard = ""
+		filterType = ""
+
+		lines := []string{"a", "b", "c", "d", "e", "f"}
+		results, err := runBatch(context.Background(), lines)
+		if err != nil {
+			t.Fatalf("runBatch returned error: %v", err)
+		}
+		if len(results) != len(lines) {
+			t.Fatalf("runBatch returned %d results, want %d", len(results), len(lines))
+		}
+		if fb.peak > int32(concurrency) {
+			t.Fatalf("peak concurrent Search calls = %d, want <= %d", fb.peak, concurrency)
+		}
+	})
+}
+
+func TestRunBatchFailFastPropagatesError(t *testing.T) {
+	withTestGlobals(t, func() {
+		fb := &fakeBackend{failFor: map[string]bool{"bad": true}}
+		selectedBackend = fb
+		concurrency = 4
+		failFast = true
+		mapFrom = ""
+		wildcard = ""
+		filterType = ""
+
+		_, err := runBatch(context.Background(), []string{"good", "bad"})
+		if err == nil {
+			t.Fatalf("runBatch returned no error, want one from the failing query")
+		}
+	})
+}
+
+func TestRunBatchContinueOnErrorSkipsFailures(t *testing.T) {
+	withTestGlobals(t, func() {
+		fb := &fakeBackend{failFor: map[string]bool{"bad": true}}
+		selectedBackend = fb
+		concurrency = 4
+		failFast = false
+		mapFrom = ""
+		wildcard = ""
+		filterType = ""
+
+		results, err := runBatch(context.Background(), []string{"good", "bad"})
+		if err != nil {
+			t.Fatalf("runBatch returned error: %v", err)
+		}
+		if len(results) != 1 || results[0].path != "good" {
+			t.Fatalf("results = %v, want a single result for the \"good\" query", results)
+		}
+	})
+}
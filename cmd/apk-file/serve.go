@@ -0,0 +1,230 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+// serveCommand implements the cli.Command interface to run apk-file as a
+// long-lived HTTP/JSON search server instead of a one-shot CLI lookup.
+type serveCommand struct {
+	listenAddr string
+	tlsCert    string
+	tlsKey     string
+	cacheSize  int
+}
+
+func (cmd *serveCommand) Name() string      { return "serve" }
+func (cmd *serveCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *serveCommand) ShortHelp() string { return "Run apk-file as an HTTP/JSON search server" }
+func (cmd *serveCommand) Hidden() bool      { return false }
+func (cmd *serveCommand) LongHelp() string {
+	return "Run apk-file as a long-lived HTTP server, exposing /search, /healthz and /metrics so CI systems and container-build pipelines can repeatedly resolve file to package questions without re-spawning the CLI."
+}
+
+func (cmd *serveCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.listenAddr, "listen-addr", ":8080", "address to serve on")
+	fs.StringVar(&cmd.tlsCert, "tls-cert", "", "TLS certificate file (enables TLS when set with -tls-key)")
+	fs.StringVar(&cmd.tlsKey, "tls-key", "", "TLS key file (enables TLS when set with -tls-cert)")
+	fs.IntVar(&cmd.cacheSize, "cache-size", 1024, "number of search responses to keep in the in-process LRU cache")
+}
+
+// Run implements cli.Command. It is the serve subcommand's entry point.
+func (cmd *serveCommand) Run(ctx context.Context, args []string) error {
+	// selectedBackend is set up by p.Before, including wiring -offline
+	// through to an *alpine.Backend, so serve benefits from the same
+	// APKINDEX cache as the one-shot CLI path.
+	s := &server{
+		backend: selectedBackend,
+		cache:   newResponseCache(cmd.cacheSize),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", s.handleSearch)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:    cmd.listenAddr,
+		Handler: mux,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		logrus.Infof("serving on %s", cmd.listenAddr)
+		if cmd.tlsCert != "" && cmd.tlsKey != "" {
+			errc <- srv.ListenAndServeTLS(cmd.tlsCert, cmd.tlsKey)
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM, syscall.SIGINT)
+
+	select {
+	case err := <-errc:
+		if err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	case sig := <-sigc:
+		logrus.Infof("received %s, shutting down gracefully", sig)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+var (
+	searchRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apk_file_search_requests_total",
+		Help: "Total number of /search requests, by distro and result status.",
+	}, []string{"distro", "status"})
+
+	searchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "apk_file_search_duration_seconds",
+		Help: "Time taken to serve a /search request.",
+	}, []string{"distro"})
+
+	cacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "apk_file_search_cache_hits_total",
+		Help: "Total number of /search requests served from the in-process cache.",
+	})
+)
+
+// server holds the state shared by every HTTP handler: the selected
+// backend and its response cache.
+type server struct {
+	backend backend.Backend
+	cache   *responseCache
+}
+
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	q := r.URL.Query()
+
+	query := backend.Query{
+		Pattern: q.Get("q"),
+		Filter:  q.Get("filter"),
+		Branch:  q.Get("branch"),
+		Repo:    q.Get("repo"),
+		Arch:    q.Get("arch"),
+	}
+	if query.Pattern == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	key := cacheKey{
+		distro:  s.backend.Name(),
+		pattern: query.Pattern,
+		filter:  query.Filter,
+		branch:  query.Branch,
+		repo:    query.Repo,
+		arch:    query.Arch,
+	}
+
+	files, ok := s.cache.get(key)
+	if ok {
+		cacheHits.Inc()
+	} else {
+		var err error
+		files, err = s.backend.Search(r.Context(), query)
+		if err != nil {
+			searchRequests.WithLabelValues(s.backend.Name(), "error").Inc()
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		s.cache.put(key, files)
+	}
+
+	searchRequests.WithLabelValues(s.backend.Name(), "ok").Inc()
+	searchDuration.WithLabelValues(s.backend.Name()).Observe(time.Since(start).Seconds())
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(files); err != nil {
+		logrus.Errorf("encoding /search response: %v", err)
+	}
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// cacheKey identifies a /search response in the response cache.
+type cacheKey struct {
+	distro, pattern, filter, branch, repo, arch string
+}
+
+// responseCache is a fixed-size, in-process LRU cache of /search results,
+// keyed by the query tuple that produced them.
+type responseCache struct {
+	mu    sync.Mutex
+	cap   int
+	ll    *list.List
+	items map[cacheKey]*list.Element
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	value []backend.FileInfo
+}
+
+func newResponseCache(capacity int) *responseCache {
+	return &responseCache{
+		cap:   capacity,
+		ll:    list.New(),
+		items: make(map[cacheKey]*list.Element),
+	}
+}
+
+func (c *responseCache) get(key cacheKey) ([]backend.FileInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*cacheEntry).value, true
+}
+
+func (c *responseCache) put(key cacheKey, value []backend.FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*cacheEntry).value = value
+		return
+	}
+
+	el := c.ll.PushFront(&cacheEntry{key: key, value: value})
+	c.items[key] = el
+
+	if c.cap > 0 && c.ll.Len() > c.cap {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheEntry).key)
+		}
+	}
+}
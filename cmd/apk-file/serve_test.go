@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+func TestResponseCacheGetPutRoundTrip(t *testing.T) {
+	c := newResponseCache(2)
+
+	key := cacheKey{distro: "alpine", pattern: "curl"}
+	if _, ok := c.get(key); ok {
+		t.Fatalf("get on empty cache returned ok=true")
+	}
+
+	want := []backend.FileInfo{{Path: "usr/bin/curl", Package: "curl"}}
+	c.put(key, want)
+
+	got, ok := c.get(key)
+	if !ok {
+		t.Fatalf("get after put returned ok=false")
+	}
+	if len(got) != 1 || got[0].Path != want[0].Path {
+		t.Fatalf("get = %v, want %v", got, want)
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResponseCache(2)
+
+	a := cacheKey{pattern: "a"}
+	b := cacheKey{pattern: "b"}
+	cc := cacheKey{pattern: "c"}
+
+	c.put(a, []backend.FileInfo{{Path: "a"}})
+	c.put(b, []backend.FileInfo{{Path: "b"}})
+
+	// Touch a so b becomes the least recently used entry.
+	if _, ok := c.get(a); !ok {
+		t.Fatalf("get(a) returned ok=false")
+	}
+
+	// Inserting a third entry should evict b, not a.
+	c.put(cc, []backend.FileInfo{{Path: "c"}})
+
+	if _, ok := c.get(b); ok {
+		t.Fatalf("get(b) returned ok=true, want evicted")
+	}
+	if _, ok := c.get(a); !ok {
+		t.Fatalf("get(a) returned ok=false, want still cached")
+	}
+	if _, ok := c.get(cc); !ok {
+		t.Fatalf("get(c) returned ok=false, want cached")
+	}
+}
+
+func TestResponseCachePutOverwritesExistingKey(t *testing.T) {
+	c := newResponseCache(2)
+	key := cacheKey{pattern: "curl"}
+
+	c.put(key, []backend.FileInfo{{Path: "old"}})
+	c.put(key, []backend.FileInfo{{Path: "new"}})
+
+	got, ok := c.get(key)
+	if !ok {
+		t.Fatalf("get returned ok=false")
+	}
+	if len(got) != 1 || got[0].Path != "new" {
+		t.Fatalf("get = %v, want a single entry with Path=new", got)
+	}
+}
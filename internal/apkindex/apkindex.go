@@ -0,0 +1,351 @@
+// Package apkindex implements an offline search backend backed by Alpine's
+// APKINDEX.tar.gz files, so contents can be queried without scraping
+// pkgs.alpinelinux.org over HTTP.
+package apkindex
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const mirrorBaseURI = "https://dl-cdn.alpinelinux.org/alpine"
+
+// FileInfo describes a single file owned by an Alpine package, in the same
+// shape the HTML scraper produces so both backends can feed the same
+// tabular pipeline.
+type FileInfo struct {
+	Path, Package, Branch, Repo, Arch string
+}
+
+// Package is a single APKINDEX record together with the file paths it owns.
+// Files are kept as a plain list per package; there is no lookup keyed by
+// file path, so Index.Search scans every package's files.
+type Package struct {
+	Name       string
+	Version    string
+	Arch       string
+	Origin     string
+	Depends    string
+	Provides   string
+	Maintainer string
+	BuildTime  string
+	Commit     string
+	Files      []string
+}
+
+// Index is an in-memory, parsed APKINDEX for a single branch/repo/arch.
+type Index struct {
+	Branch, Repo, Arch string
+	Packages           []Package
+}
+
+// Open downloads (or reuses a cached copy of) the APKINDEX for the given
+// branch/repo/arch and parses it into an Index.
+func Open(branch, repo, arch string) (*Index, error) {
+	f, err := fetch(branch, repo, arch)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pkgs, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing APKINDEX for %s/%s/%s: %v", branch, repo, arch, err)
+	}
+
+	return &Index{
+		Branch:   branch,
+		Repo:     repo,
+		Arch:     arch,
+		Packages: pkgs,
+	}, nil
+}
+
+// Search returns every file in the index whose path matches pattern, using
+// path.Match glob semantics. filter restricts matching to "file", "path" or
+// "package"; an empty filter matches against the full file path.
+//
+// path.Match's "*" only matches within a single path element, so a pattern
+// with no directory separator (e.g. "*curl*") would never match a real,
+// multi-directory Alpine path (e.g. "usr/bin/curl"). When pattern has no
+// "/" of its own, it is matched against the file's base name instead of
+// its full path, so callers don't have to know how deep a file lives.
+func (idx *Index) Search(pattern, filter string) ([]FileInfo, error) {
+	results := []FileInfo{}
+	for _, pkg := range idx.Packages {
+		for _, file := range pkg.Files {
+			candidate := file
+			switch {
+			case filter == "package":
+				candidate = pkg.Name
+			case filter == "file" || !strings.Contains(pattern, "/"):
+				candidate = path.Base(file)
+			}
+
+			ok, err := matchPath(pattern, candidate)
+			if err != nil {
+				return nil, fmt.Errorf("matching %q against %q: %v", pattern, candidate, err)
+			}
+			if !ok {
+				continue
+			}
+
+			results = append(results, FileInfo{
+				Path:    file,
+				Package: pkg.Name,
+				Branch:  idx.Branch,
+				Repo:    idx.Repo,
+				Arch:    idx.Arch,
+			})
+		}
+	}
+	return results, nil
+}
+
+// matchPath matches pattern against candidate element by element with
+// path.Match, rather than as a single path.Match call over the whole
+// strings. That lets a caller ask with fewer elements than the real path
+// has (e.g. "bin/curl" or "*/usr/bin/curl" for a file actually stored as
+// "usr/bin/curl") and still get a match, by aligning pattern's elements
+// against candidate's trailing elements instead of requiring the same
+// element count throughout. A pattern whose first element is a bare "*"
+// (the alpine backend's way of saying "preceded by anything") is dropped
+// before aligning, since it doesn't stand for one specific directory.
+// A leading "/" is not dropped: "/usr/bin/curl" keeps an empty first
+// element and so does not match "usr/bin/curl".
+func matchPath(pattern, candidate string) (bool, error) {
+	patElems := strings.Split(pattern, "/")
+	if patElems[0] == "*" {
+		patElems = patElems[1:]
+	}
+
+	candElems := strings.Split(candidate, "/")
+	if len(patElems) > len(candElems) {
+		return false, nil
+	}
+	candElems = candElems[len(candElems)-len(patElems):]
+
+	for i, p := range patElems {
+		ok, err := path.Match(p, candElems[i])
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// parse reads the untarred APKINDEX file and turns its RFC822-style records
+// into Packages. Each file entry is reconstructed by concatenating an F:
+// (folder) line with the R: (file) lines that follow it, until the next F:
+// line or the end of the record.
+func parse(r io.Reader) ([]Package, error) {
+	pkgs := []Package{}
+	cur := Package{}
+	folder := ""
+
+	flush := func() {
+		if cur.Name != "" {
+			pkgs = append(pkgs, cur)
+		}
+		cur = Package{}
+		folder = ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		key, value := line[0], line[2:]
+
+		switch key {
+		case 'P':
+			cur.Name = value
+		case 'V':
+			cur.Version = value
+		case 'A':
+			cur.Arch = value
+		case 'o':
+			cur.Origin = value
+		case 'D':
+			cur.Depends = value
+		case 'p':
+			cur.Provides = value
+		case 'M':
+			cur.Maintainer = value
+		case 't':
+			cur.BuildTime = value
+		case 'c':
+			cur.Commit = value
+		case 'F':
+			folder = value
+		case 'R':
+			cur.Files = append(cur.Files, path.Join(folder, value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return pkgs, nil
+}
+
+// fetch returns a reader for the untarred APKINDEX file for the given
+// branch/repo/arch, downloading and caching the tarball under the XDG cache
+// dir and revalidating it with ETag/Last-Modified on every call.
+func fetch(branch, repo, arch string) (io.ReadCloser, error) {
+	cacheDir, err := cacheDirFor(branch, repo, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	tarballPath := filepath.Join(cacheDir, "APKINDEX.tar.gz")
+	metaPath := filepath.Join(cacheDir, "APKINDEX.tar.gz.meta")
+
+	uri := fmt.Sprintf("%s/%s/%s/%s/APKINDEX.tar.gz", mirrorBaseURI, branch, repo, arch)
+
+	if err := revalidate(uri, tarballPath, metaPath); err != nil {
+		return nil, err
+	}
+
+	return untarAPKINDEX(tarballPath)
+}
+
+// revalidate downloads uri into tarballPath if it is missing, or re-fetches
+// it when the cached copy's ETag/Last-Modified (stored in metaPath) is
+// stale. A 304 response leaves the cached tarball untouched.
+func revalidate(uri, tarballPath, metaPath string) error {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	// Only send validators while the tarball itself is still cached, so a
+	// 304 never leaves a missing tarball missing.
+	_, statErr := os.Stat(tarballPath)
+	if meta, err := os.ReadFile(metaPath); err == nil && statErr == nil {
+		lines := strings.SplitN(string(meta), "\n", 2)
+		if len(lines) == 2 {
+			if lines[0] != "" {
+				req.Header.Set("If-None-Match", lines[0])
+			}
+			if lines[1] != "" {
+				req.Header.Set("If-Modified-Since", lines[1])
+			}
+		}
+	}
+
+	logrus.Debugf("fetching %s", uri)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %v", uri, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		logrus.Debugf("%s not modified, using cache", uri)
+		return nil
+	case http.StatusOK:
+		// fall through and write the new copy.
+	default:
+		return fmt.Errorf("fetching %s: unexpected status %s", uri, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(tarballPath), "apkindex-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), tarballPath); err != nil {
+		return err
+	}
+
+	meta := resp.Header.Get("ETag") + "\n" + resp.Header.Get("Last-Modified")
+	return os.WriteFile(metaPath, []byte(meta), 0644)
+}
+
+// untarAPKINDEX extracts the APKINDEX member from the gzip+tar archive and
+// returns it as a standalone reader, buffering it in memory since it is
+// small relative to the tarball itself.
+func untarAPKINDEX(tarballPath string) (io.ReadCloser, error) {
+	f, err := os.Open(tarballPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name != "APKINDEX" {
+			continue
+		}
+
+		buf := new(strings.Builder)
+		if _, err := io.Copy(buf, tr); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(strings.NewReader(buf.String())), nil
+	}
+
+	return nil, fmt.Errorf("APKINDEX member not found in %s", tarballPath)
+}
+
+// cacheDirFor returns the XDG cache directory used to store the
+// downloaded APKINDEX.tar.gz for a given branch/repo/arch, creating it if
+// it does not already exist.
+func cacheDirFor(branch, repo, arch string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(base, "apk-file", "apkindex", branch, repo, arch)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,146 @@
+package apkindex
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sampleIndex() *Index {
+	return &Index{
+		Branch: "v3.8",
+		Repo:   "main",
+		Arch:   "x86_64",
+		Packages: []Package{
+			{
+				Name: "curl",
+				Files: []string{
+					"usr/bin/curl",
+					"usr/share/doc/curl/README",
+				},
+			},
+			{
+				Name: "libcurl",
+				Files: []string{
+					"usr/lib/libcurl.so.4",
+					"usr/lib/libcurl.so.4.5.0",
+				},
+			},
+		},
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := sampleIndex()
+
+	tests := []struct {
+		name    string
+		pattern string
+		filter  string
+		want    []string
+	}{
+		{
+			name:    "basename query with no directory matches a multi-level path",
+			pattern: "*curl*",
+			filter:  "",
+			want:    []string{"usr/bin/curl", "usr/lib/libcurl.so.4", "usr/lib/libcurl.so.4.5.0"},
+		},
+		{
+			name:    "file filter matches against the base name only",
+			pattern: "curl",
+			filter:  "file",
+			want:    []string{"usr/bin/curl"},
+		},
+		{
+			name:    "path filter matches the full, slash-separated path",
+			pattern: "usr/bin/*",
+			filter:  "path",
+			want:    []string{"usr/bin/curl"},
+		},
+		{
+			name:    "absolute directory-qualified query matches despite its extra leading element",
+			pattern: "*/usr/bin/curl*",
+			filter:  "",
+			want:    []string{"usr/bin/curl"},
+		},
+		{
+			name:    "relative directory-qualified query matches a path with more elements",
+			pattern: "*bin/curl*",
+			filter:  "",
+			want:    []string{"usr/bin/curl"},
+		},
+		{
+			name:    "package filter matches the owning package name",
+			pattern: "libcurl",
+			filter:  "package",
+			want:    []string{"usr/lib/libcurl.so.4", "usr/lib/libcurl.so.4.5.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := idx.Search(tt.pattern, tt.filter)
+			if err != nil {
+				t.Fatalf("Search(%q, %q) returned error: %v", tt.pattern, tt.filter, err)
+			}
+
+			got := make([]string, 0, len(results))
+			for _, r := range results {
+				got = append(got, r.Path)
+			}
+			sort.Strings(got)
+			sort.Strings(tt.want)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search(%q, %q) = %v, want %v", tt.pattern, tt.filter, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Search(%q, %q) = %v, want %v", tt.pattern, tt.filter, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseReconstructsFilesAcrossMultipleFolderLines(t *testing.T) {
+	const raw = `P:curl
+V:7.61.1-r0
+A:x86_64
+F:usr/bin
+R:curl
+F:usr/share/doc/curl
+R:README
+R:CHANGES
+
+P:libcurl
+V:7.61.1-r0
+A:x86_64
+F:usr/lib
+R:libcurl.so.4
+R:libcurl.so.4.5.0
+`
+
+	pkgs, err := parse(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+
+	if len(pkgs) != 2 {
+		t.Fatalf("parse returned %d packages, want 2", len(pkgs))
+	}
+
+	curl := pkgs[0]
+	if curl.Name != "curl" {
+		t.Fatalf("pkgs[0].Name = %q, want curl", curl.Name)
+	}
+	wantFiles := []string{"usr/bin/curl", "usr/share/doc/curl/README", "usr/share/doc/curl/CHANGES"}
+	if len(curl.Files) != len(wantFiles) {
+		t.Fatalf("curl.Files = %v, want %v", curl.Files, wantFiles)
+	}
+	for i, f := range wantFiles {
+		if curl.Files[i] != f {
+			t.Fatalf("curl.Files[%d] = %q, want %q", i, curl.Files[i], f)
+		}
+	}
+}
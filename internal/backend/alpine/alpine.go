@@ -0,0 +1,159 @@
+// Package alpine implements the backend.Backend interface against Alpine's
+// pkgs.alpinelinux.org contents search, with an offline mode backed by
+// internal/apkindex.
+package alpine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/apkindex"
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+const contentsSearchURI = "https://pkgs.alpinelinux.org/contents"
+
+var (
+	validArches   = []string{"x86", "x86_64", "armhf", "aarch64", "ppc64le", "s390x"}
+	validRepos    = []string{"main", "community", "testing"}
+	validBranches = []string{"edge", "v3.8", "v3.7", "v3.6", "v3.5", "v3.4", "v3.3"}
+)
+
+func init() {
+	backend.Register("alpine", func() backend.Backend { return &Backend{} })
+}
+
+// Backend searches Alpine package contents, either by scraping
+// pkgs.alpinelinux.org or, when Offline is set, against a locally cached
+// APKINDEX.
+type Backend struct {
+	// Offline selects the cached APKINDEX backend instead of the HTML scraper.
+	Offline bool
+}
+
+// Name implements backend.Backend.
+func (b *Backend) Name() string { return "alpine" }
+
+// ValidArches implements backend.Backend.
+func (b *Backend) ValidArches() []string { return validArches }
+
+// ValidRepos implements backend.Backend.
+func (b *Backend) ValidRepos() []string { return validRepos }
+
+// ValidBranches implements backend.Backend.
+func (b *Backend) ValidBranches() []string { return validBranches }
+
+// Search implements backend.Backend.
+func (b *Backend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	file, dir := getFileAndPath(q.Pattern)
+
+	if b.Offline {
+		return b.searchOffline(file, dir, q)
+	}
+	return b.searchOnline(ctx, file, dir, q)
+}
+
+func (b *Backend) searchOffline(file, dir string, q backend.Query) ([]backend.FileInfo, error) {
+	idx, err := apkindex.Open(q.Branch, q.Repo, q.Arch)
+	if err != nil {
+		return nil, fmt.Errorf("opening offline index: %v", err)
+	}
+
+	pattern := file
+	if dir != "" {
+		pattern = strings.TrimSuffix(dir, "*") + "/" + strings.TrimPrefix(file, "*")
+	}
+
+	matches, err := idx.Search(pattern, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]backend.FileInfo, 0, len(matches))
+	for _, m := range matches {
+		files = append(files, backend.FileInfo{
+			Path:    m.Path,
+			Package: m.Package,
+			Branch:  m.Branch,
+			Repo:    m.Repo,
+			Arch:    m.Arch,
+			Distro:  "alpine",
+		})
+	}
+	return files, nil
+}
+
+func (b *Backend) searchOnline(ctx context.Context, file, dir string, q backend.Query) ([]backend.FileInfo, error) {
+	values := url.Values{
+		"file":   {file},
+		"path":   {dir},
+		"branch": {q.Branch},
+		"repo":   {q.Repo},
+		"arch":   {q.Arch},
+	}
+
+	uri := fmt.Sprintf("%s?%s", contentsSearchURI, values.Encode())
+	logrus.Debugf("requesting from %s", uri)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s failed: %v", uri, err)
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("creating document failed: %v", err)
+	}
+
+	return getFilesInfo(doc), nil
+}
+
+func getFilesInfo(d *goquery.Document) []backend.FileInfo {
+	files := []backend.FileInfo{}
+	d.Find(".pure-table tr:not(:first-child)").Each(func(j int, l *goquery.Selection) {
+		f := backend.FileInfo{Distro: "alpine"}
+		rows := l.Find("td")
+		rows.Each(func(i int, s *goquery.Selection) {
+			switch i {
+			case 0:
+				f.Path = s.Text()
+			case 1:
+				f.Package = s.Text()
+			case 2:
+				f.Branch = s.Text()
+			case 3:
+				f.Repo = s.Text()
+			case 4:
+				f.Arch = s.Text()
+			default:
+				logrus.Warnf("Unmapped value for column %d with value %s", i, s.Text())
+			}
+		})
+		files = append(files, f)
+	})
+	return files
+}
+
+func getFileAndPath(arg string) (file string, dir string) {
+	file = "*" + path.Base(arg) + "*"
+	dir = path.Dir(arg)
+	if dir != "" && dir != "." {
+		dir = "*" + dir
+		file = strings.TrimPrefix(file, "*")
+	} else {
+		dir = ""
+	}
+	return file, dir
+}
@@ -0,0 +1,88 @@
+// Package arch implements the backend.Backend interface for Arch Linux,
+// shelling out to `pacman -F` (the pacman-contrib file database) when it is
+// available locally.
+package arch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+func init() {
+	backend.Register("arch", func() backend.Backend { return &Backend{} })
+}
+
+// Backend searches Arch Linux package contents via the local pacman file
+// database (`pacman -F`, kept in sync with `pacman -Fy`).
+type Backend struct{}
+
+// Name implements backend.Backend.
+func (b *Backend) Name() string { return "arch" }
+
+// ValidArches implements backend.Backend. Arch Linux only ships x86_64.
+func (b *Backend) ValidArches() []string { return []string{"x86_64"} }
+
+// ValidRepos implements backend.Backend.
+func (b *Backend) ValidRepos() []string { return []string{"core", "extra", "community", "multilib"} }
+
+// ValidBranches implements backend.Backend. Arch is rolling release.
+func (b *Backend) ValidBranches() []string { return nil }
+
+// Search implements backend.Backend by shelling out to `pacman -F`.
+func (b *Backend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	cmd := exec.CommandContext(ctx, "pacman", "-F", "--machinereadable", q.Pattern)
+	logrus.Debugf("running %s", strings.Join(cmd.Args, " "))
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running pacman -F: %v (is pacman-contrib installed and `pacman -Fy` up to date?)", err)
+	}
+
+	return parseMachineReadable(out, q)
+}
+
+// parseMachineReadable parses `pacman -F --machinereadable` output, where
+// each match is a NUL-separated "repo\0pkgname\0pkgver\0filepath" record
+// followed by a newline.
+func parseMachineReadable(out []byte, q backend.Query) ([]backend.FileInfo, error) {
+	files := []backend.FileInfo{}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\x00")
+		if len(fields) < 4 {
+			continue
+		}
+
+		repo, pkg, filePath := fields[0], fields[1], fields[3]
+		if q.Filter == "file" && path.Base(filePath) != path.Base(q.Pattern) {
+			continue
+		}
+
+		files = append(files, backend.FileInfo{
+			Path:    "/" + filePath,
+			Package: pkg,
+			Repo:    repo,
+			Arch:    "x86_64",
+			Distro:  "arch",
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
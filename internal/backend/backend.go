@@ -0,0 +1,82 @@
+// Package backend defines the pluggable package-search abstraction that
+// lets apk-file look up file ownership across more than just Alpine, and
+// the registry used to dispatch a --distro flag to the right implementation.
+package backend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Query is the distro-agnostic search request passed to a Backend. Not
+// every field is meaningful to every backend (e.g. Branch has no Arch Linux
+// equivalent, since Arch is rolling release); backends ignore the fields
+// they don't understand.
+type Query struct {
+	Pattern string
+	Filter  string
+	Branch  string
+	Repo    string
+	Arch    string
+}
+
+// FileInfo describes a single file owned by a package, as returned by any
+// Backend, with Distro identifying which one produced it.
+type FileInfo struct {
+	Path    string `json:"file"`
+	Package string `json:"package"`
+	Branch  string `json:"branch"`
+	Repo    string `json:"repository"`
+	Arch    string `json:"architecture"`
+	Distro  string `json:"distro"`
+}
+
+// Backend is a single distro's file-to-package search implementation.
+type Backend interface {
+	// Name returns the distro name this backend registers under, e.g. "alpine".
+	Name() string
+
+	// Search looks up files matching q and returns the owning packages.
+	Search(ctx context.Context, q Query) ([]FileInfo, error)
+
+	// ValidArches, ValidRepos and ValidBranches return the values this
+	// backend accepts for the corresponding Query fields, for flag
+	// validation. A nil/empty slice means the backend does not constrain
+	// that field.
+	ValidArches() []string
+	ValidRepos() []string
+	ValidBranches() []string
+}
+
+// Factory constructs a new Backend instance.
+type Factory func() Backend
+
+var registry = map[string]Factory{}
+
+// Register adds a backend factory under name. It is meant to be called from
+// the init() of each backend's package.
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Get returns a new Backend instance for name, or an error if no backend is
+// registered under that name.
+func Get(name string) (Backend, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a known distro, allowed: %v", name, Names())
+	}
+	return factory(), nil
+}
+
+// Names returns the registered backend names, sorted so that help and
+// error text using them is stable across runs.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,100 @@
+// Package debian implements the backend.Backend interface against
+// packages.debian.org's contents search.
+package debian
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+const contentsSearchURI = "https://packages.debian.org/search"
+
+var (
+	validArches   = []string{"amd64", "i386", "arm64", "armhf", "armel", "mips64el", "ppc64el", "s390x"}
+	validRepos    = []string{"main", "contrib", "non-free"}
+	validBranches = []string{"stable", "testing", "unstable", "oldstable"}
+)
+
+func init() {
+	backend.Register("debian", func() backend.Backend { return &Backend{} })
+}
+
+// Backend searches Debian package contents via packages.debian.org's
+// "search contents" form.
+type Backend struct{}
+
+// Name implements backend.Backend.
+func (b *Backend) Name() string { return "debian" }
+
+// ValidArches implements backend.Backend.
+func (b *Backend) ValidArches() []string { return validArches }
+
+// ValidRepos implements backend.Backend. Debian calls these "components".
+func (b *Backend) ValidRepos() []string { return validRepos }
+
+// ValidBranches implements backend.Backend. Debian calls these "suites".
+func (b *Backend) ValidBranches() []string { return validBranches }
+
+// Search implements backend.Backend.
+func (b *Backend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	values := url.Values{
+		"searchon": {"contents"},
+		"keywords": {strings.Trim(q.Pattern, "*")},
+		"mode":     {"filename"},
+		"suite":    {q.Branch},
+		"arch":     {q.Arch},
+	}
+
+	uri := fmt.Sprintf("%s?%s", contentsSearchURI, values.Encode())
+	logrus.Debugf("requesting from %s", uri)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s failed: %v", uri, err)
+	}
+	defer resp.Body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("creating document failed: %v", err)
+	}
+
+	return parseContents(doc, q), nil
+}
+
+// parseContents walks the "search contents" results table, whose rows pair
+// a file path with a comma-separated list of package links.
+func parseContents(d *goquery.Document, q backend.Query) []backend.FileInfo {
+	files := []backend.FileInfo{}
+	d.Find("table.contenttable tr:not(:first-child)").Each(func(i int, row *goquery.Selection) {
+		cells := row.Find("td")
+		if cells.Length() < 2 {
+			return
+		}
+
+		filePath := strings.TrimSpace(cells.Eq(0).Text())
+		cells.Eq(1).Find("a").Each(func(j int, pkgLink *goquery.Selection) {
+			files = append(files, backend.FileInfo{
+				Path:    "/" + filePath,
+				Package: strings.TrimSpace(pkgLink.Text()),
+				Branch:  q.Branch,
+				Repo:    q.Repo,
+				Arch:    q.Arch,
+				Distro:  "debian",
+			})
+		})
+	})
+	return files
+}
@@ -0,0 +1,87 @@
+// Package fedora implements the backend.Backend interface for Fedora,
+// shelling out to `dnf repoquery -f` to resolve files to packages.
+package fedora
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+var validArches = []string{"x86_64", "aarch64", "armhfp", "ppc64le", "s390x", "i686"}
+
+func init() {
+	backend.Register("fedora", func() backend.Backend { return &Backend{} })
+}
+
+// Backend searches Fedora package contents via the local dnf repoquery
+// cache.
+type Backend struct{}
+
+// Name implements backend.Backend.
+func (b *Backend) Name() string { return "fedora" }
+
+// ValidArches implements backend.Backend.
+func (b *Backend) ValidArches() []string { return validArches }
+
+// ValidRepos implements backend.Backend.
+func (b *Backend) ValidRepos() []string { return []string{"fedora", "updates", "rawhide"} }
+
+// ValidBranches implements backend.Backend. Fedora releases are numbered.
+func (b *Backend) ValidBranches() []string { return nil }
+
+// Search implements backend.Backend by shelling out to `dnf repoquery -f`.
+func (b *Backend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	args := []string{"repoquery", "-f", q.Pattern, "--qf", "%{name}|%{arch}|%{reponame}"}
+	if q.Repo != "" {
+		args = append(args, "--repo", q.Repo)
+	}
+	if q.Arch != "" {
+		args = append(args, "--arch", q.Arch)
+	}
+
+	cmd := exec.CommandContext(ctx, "dnf", args...)
+	logrus.Debugf("running %s", strings.Join(cmd.Args, " "))
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running dnf repoquery: %v (is dnf installed with repo metadata synced?)", err)
+	}
+
+	return parseRepoquery(out, q), nil
+}
+
+// parseRepoquery parses one "name|arch|reponame" record per line, as
+// produced by the --qf format string passed to dnf repoquery.
+func parseRepoquery(out []byte, q backend.Query) []backend.FileInfo {
+	files := []backend.FileInfo{}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "|", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		files = append(files, backend.FileInfo{
+			Path:    q.Pattern,
+			Package: fields[0],
+			Arch:    fields[1],
+			Repo:    fields[2],
+			Distro:  "fedora",
+		})
+	}
+
+	return files
+}
@@ -0,0 +1,203 @@
+// Package lddtree walks the transitive shared-library dependencies of an
+// ELF binary (its DT_NEEDED entries) and resolves each one to the distro
+// package that provides it, the way `lddtree`/`ldd` does for the dynamic
+// linker but backed by a package-contents search instead of the local
+// filesystem alone.
+package lddtree
+
+import (
+	"context"
+	"debug/elf"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+// Resolution is a single DT_NEEDED edge in the dependency closure: Binary
+// needs NeededSoname, which was found on disk at ResolvedPath (if at all),
+// and is owned by Package according to the backend.
+type Resolution struct {
+	Binary       string
+	NeededSoname string
+	ResolvedPath string
+	Package      string
+}
+
+// Resolve walks the transitive shared-library closure of binaryPath,
+// resolving every needed soname against b's contents search and
+// recursing into any dependency it can also find under sysroot.
+func Resolve(ctx context.Context, b backend.Backend, binaryPath, sysroot string, q backend.Query) ([]Resolution, error) {
+	results := []Resolution{}
+	visited := map[string]bool{}
+
+	queue := []string{binaryPath}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+
+		real, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if visited[real] {
+			continue
+		}
+		visited[real] = true
+
+		needed, rpaths, runpaths, err := readDynamic(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+
+		searchPaths := expandPaths(rpaths, path, sysroot)
+		searchPaths = append(searchPaths, expandPaths(runpaths, path, sysroot)...)
+		searchPaths = append(searchPaths, defaultLibDirs(sysroot)...)
+
+		for _, soname := range needed {
+			resolvedPath := findOnDisk(soname, searchPaths)
+
+			pkg, err := lookupPackage(ctx, b, soname, q)
+			if err != nil {
+				return nil, err
+			}
+			if pkg == "" {
+				logrus.Warnf("%s: no package provides %s", path, soname)
+			}
+
+			results = append(results, Resolution{
+				Binary:       path,
+				NeededSoname: soname,
+				ResolvedPath: resolvedPath,
+				Package:      pkg,
+			})
+
+			if resolvedPath != "" {
+				queue = append(queue, resolvedPath)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// lookupPackage asks b which package owns soname, returning "" if none do.
+// The pattern is a bare basename with no directory component: with filter
+// "file" the offline APKINDEX backend matches the pattern against a file's
+// base name only, so a pattern containing "/" would never match there.
+func lookupPackage(ctx context.Context, b backend.Backend, soname string, q backend.Query) (string, error) {
+	matches, err := b.Search(ctx, backend.Query{
+		Pattern: soname,
+		Filter:  "file",
+		Branch:  q.Branch,
+		Repo:    q.Repo,
+		Arch:    q.Arch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %v", soname, err)
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0].Package, nil
+}
+
+// FindPackageBinary resolves pkg to one of its binaries already present
+// under sysroot, so Resolve has a path it can elf.Open. It is used when
+// lddtree mode is given a package name instead of a binary path: b's
+// contents search has no "list files owned by a package" query, so this
+// walks the bin/sbin directories for matches owned by pkg and picks the
+// first one that actually exists on disk.
+func FindPackageBinary(ctx context.Context, b backend.Backend, pkg, sysroot string, q backend.Query) (string, error) {
+	matches, err := b.Search(ctx, backend.Query{
+		Pattern: "*bin/*",
+		Filter:  "path",
+		Branch:  q.Branch,
+		Repo:    q.Repo,
+		Arch:    q.Arch,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, m := range matches {
+		if m.Package != pkg {
+			continue
+		}
+		candidate := filepath.Join(sysroot, m.Path)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no on-disk binary owned by package %q found under %s", pkg, sysroot)
+}
+
+// readDynamic returns the DT_NEEDED sonames and the DT_RPATH/DT_RUNPATH
+// search paths recorded in path's dynamic section.
+func readDynamic(path string) (needed, rpaths, runpaths []string, err error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	needed, err = f.DynString(elf.DT_NEEDED)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rpaths, err = f.DynString(elf.DT_RPATH)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	runpaths, err = f.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return needed, rpaths, runpaths, nil
+}
+
+// expandPaths resolves $ORIGIN in each of paths (a colon-separated
+// RPATH/RUNPATH entry list) to binaryPath's directory, and roots the
+// result under sysroot.
+func expandPaths(paths []string, binaryPath, sysroot string) []string {
+	dirs := []string{}
+	origin := filepath.Dir(binaryPath)
+
+	for _, entry := range paths {
+		for _, dir := range strings.Split(entry, ":") {
+			dir = strings.ReplaceAll(dir, "$ORIGIN", origin)
+			dir = strings.ReplaceAll(dir, "${ORIGIN}", origin)
+			dirs = append(dirs, filepath.Join(sysroot, dir))
+		}
+	}
+	return dirs
+}
+
+// defaultLibDirs is the standard library search path, rooted under
+// sysroot, searched after a binary's own RPATH/RUNPATH entries.
+func defaultLibDirs(sysroot string) []string {
+	return []string{
+		filepath.Join(sysroot, "lib"),
+		filepath.Join(sysroot, "usr", "lib"),
+		filepath.Join(sysroot, "lib64"),
+		filepath.Join(sysroot, "usr", "lib64"),
+	}
+}
+
+// findOnDisk returns the first existing dir/soname among dirs, or "" if
+// soname can't be found locally.
+func findOnDisk(soname string, dirs []string) string {
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, soname)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
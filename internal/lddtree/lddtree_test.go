@@ -0,0 +1,193 @@
+package lddtree
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sniperkit/snk.fork.genuinetools-apk-file/internal/backend"
+)
+
+// fakeBackend is a minimal backend.Backend that serves a fixed set of
+// FileInfo matches, used to exercise FindPackageBinary without a real
+// backend.
+type fakeBackend struct {
+	matches []backend.FileInfo
+}
+
+func (b *fakeBackend) Name() string { return "fake" }
+func (b *fakeBackend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	return b.matches, nil
+}
+func (b *fakeBackend) ValidArches() []string   { return nil }
+func (b *fakeBackend) ValidRepos() []string    { return nil }
+func (b *fakeBackend) ValidBranches() []string { return nil }
+
+// offlineLikeBackend simulates the offline APKINDEX backend under filter
+// "file", where a pattern is matched against a file's base name only, so a
+// pattern with a "/" in it never matches anything. It's used to prove lookupPackage's
+// bare-basename pattern actually finds a soname's owning package there.
+type offlineLikeBackend struct {
+	files []backend.FileInfo
+}
+
+func (b *offlineLikeBackend) Name() string { return "offline-like" }
+func (b *offlineLikeBackend) Search(ctx context.Context, q backend.Query) ([]backend.FileInfo, error) {
+	if strings.Contains(q.Pattern, "/") {
+		return nil, nil
+	}
+	var matches []backend.FileInfo
+	for _, f := range b.files {
+		if path.Base(f.Path) == q.Pattern {
+			matches = append(matches, f)
+		}
+	}
+	return matches, nil
+}
+func (b *offlineLikeBackend) ValidArches() []string   { return nil }
+func (b *offlineLikeBackend) ValidRepos() []string    { return nil }
+func (b *offlineLikeBackend) ValidBranches() []string { return nil }
+
+func TestExpandPathsResolvesOrigin(t *testing.T) {
+	binaryPath := "/opt/app/bin/myapp"
+
+	got := expandPaths([]string{"$ORIGIN/../lib:${ORIGIN}/plugins"}, binaryPath, "/")
+	want := []string{"/opt/app/lib", "/opt/app/bin/plugins"}
+
+	if len(got) != len(want) {
+		t.Fatalf("expandPaths = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expandPaths[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExpandPathsRootsUnderSysroot(t *testing.T) {
+	got := expandPaths([]string{"/usr/lib"}, "/bin/myapp", "/sysroot")
+	want := []string{"/sysroot/usr/lib"}
+
+	if len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("expandPaths = %v, want %v", got, want)
+	}
+}
+
+func TestDefaultLibDirsRootedUnderSysroot(t *testing.T) {
+	dirs := defaultLibDirs("/sysroot")
+	want := []string{
+		filepath.Join("/sysroot", "lib"),
+		filepath.Join("/sysroot", "usr", "lib"),
+		filepath.Join("/sysroot", "lib64"),
+		filepath.Join("/sysroot", "usr", "lib64"),
+	}
+	if len(dirs) != len(want) {
+		t.Fatalf("defaultLibDirs = %v, want %v", dirs, want)
+	}
+	for i := range want {
+		if dirs[i] != want[i] {
+			t.Fatalf("defaultLibDirs[%d] = %q, want %q", i, dirs[i], want[i])
+		}
+	}
+}
+
+func TestFindOnDiskReturnsFirstExistingMatch(t *testing.T) {
+	dir := t.TempDir()
+	libDir := filepath.Join(dir, "lib")
+	if err := os.Mkdir(libDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	libPath := filepath.Join(libDir, "libfoo.so")
+	if err := os.WriteFile(libPath, []byte("fake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	missingDir := filepath.Join(dir, "nope")
+
+	got := findOnDisk("libfoo.so", []string{missingDir, libDir})
+	if got != libPath {
+		t.Fatalf("findOnDisk = %q, want %q", got, libPath)
+	}
+}
+
+func TestFindOnDiskReturnsEmptyWhenNotFound(t *testing.T) {
+	dir := t.TempDir()
+	if got := findOnDisk("libfoo.so", []string{dir}); got != "" {
+		t.Fatalf("findOnDisk = %q, want \"\"", got)
+	}
+}
+
+func TestFindOnDiskSkipsDirectories(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "libfoo.so"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := findOnDisk("libfoo.so", []string{dir}); got != "" {
+		t.Fatalf("findOnDisk = %q, want \"\" for a directory named like the soname", got)
+	}
+}
+
+func TestFindPackageBinaryReturnsFirstOnDiskMatch(t *testing.T) {
+	sysroot := t.TempDir()
+	binDir := filepath.Join(sysroot, "usr", "bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	binPath := filepath.Join(binDir, "myapp")
+	if err := os.WriteFile(binPath, []byte("fake"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &fakeBackend{matches: []backend.FileInfo{
+		{Path: "/usr/bin/notmine", Package: "other"},
+		{Path: "/usr/bin/myapp", Package: "myapp"},
+	}}
+
+	got, err := FindPackageBinary(context.Background(), b, "myapp", sysroot, backend.Query{})
+	if err != nil {
+		t.Fatalf("FindPackageBinary returned error: %v", err)
+	}
+	if got != binPath {
+		t.Fatalf("FindPackageBinary = %q, want %q", got, binPath)
+	}
+}
+
+func TestFindPackageBinaryErrorsWhenNothingOnDisk(t *testing.T) {
+	b := &fakeBackend{matches: []backend.FileInfo{
+		{Path: "/usr/bin/myapp", Package: "myapp"},
+	}}
+
+	if _, err := FindPackageBinary(context.Background(), b, "myapp", t.TempDir(), backend.Query{}); err == nil {
+		t.Fatal("FindPackageBinary returned no error, want one for a package with no on-disk binary")
+	}
+}
+
+func TestLookupPackageMatchesOfflineBackendByBareBasename(t *testing.T) {
+	b := &offlineLikeBackend{files: []backend.FileInfo{
+		{Path: "lib/libc.so.6", Package: "musl"},
+	}}
+
+	pkg, err := lookupPackage(context.Background(), b, "libc.so.6", backend.Query{})
+	if err != nil {
+		t.Fatalf("lookupPackage returned error: %v", err)
+	}
+	if pkg != "musl" {
+		t.Fatalf("lookupPackage = %q, want %q", pkg, "musl")
+	}
+}
+
+func TestLookupPackageReturnsEmptyWhenNothingProvidesSoname(t *testing.T) {
+	b := &offlineLikeBackend{}
+
+	pkg, err := lookupPackage(context.Background(), b, "libfoo.so.1", backend.Query{})
+	if err != nil {
+		t.Fatalf("lookupPackage returned error: %v", err)
+	}
+	if pkg != "" {
+		t.Fatalf("lookupPackage = %q, want \"\"", pkg)
+	}
+}
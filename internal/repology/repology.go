@@ -0,0 +1,228 @@
+// Package repology translates package names between distros using the
+// Repology public API (https://repology.org/api/v1), so a name typed in
+// one distro's convention (e.g. Debian's "libssl-dev") can be resolved to
+// its Alpine equivalent (e.g. "openssl-dev") and back.
+package repology
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	apiBaseURI = "https://repology.org/api/v1"
+
+	// cacheTTL bounds how long a cached Repology response is reused before
+	// being refetched, to stay polite to the public API.
+	cacheTTL = 24 * time.Hour
+)
+
+// Package is a single entry in a Repology project's package list.
+type Package struct {
+	Repo        string `json:"repo"`
+	SrcName     string `json:"srcname"`
+	BinName     string `json:"binname"`
+	VisibleName string `json:"visiblename"`
+	Version     string `json:"version"`
+	Status      string `json:"status"`
+}
+
+// Name returns the package name to use for the given entry, preferring the
+// binary package name and falling back to the visible/source name.
+func (p Package) Name() string {
+	if p.BinName != "" {
+		return p.BinName
+	}
+	if p.VisibleName != "" {
+		return p.VisibleName
+	}
+	return p.SrcName
+}
+
+// Resolve looks up name on Repology and returns the package names known to
+// the given target distro's repos (e.g. distro "alpine" matches Repology
+// repos named "alpine_edge", "alpine_3_18", etc). If from is non-empty, the
+// fuzzy fallback search (used when name isn't itself a Repology project) is
+// restricted to projects that also have a package in a repo matching from,
+// so a name typed in one distro's convention doesn't get mapped to an
+// unrelated project of the same name in another.
+func Resolve(name, from, distro string) ([]string, error) {
+	pkgs, err := project(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasRepo(pkgs, distro) {
+		// The exact project name didn't exist on Repology (or has no
+		// package for the target distro); fall back to the fuzzy project
+		// search and retry with its best match.
+		matched, err := search(name, from)
+		if err != nil {
+			return nil, err
+		}
+		if matched == "" {
+			return nil, fmt.Errorf("repology: no project found for %q", name)
+		}
+		pkgs, err = project(matched)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, pkg := range pkgs {
+		if !strings.HasPrefix(pkg.Repo, distro) {
+			continue
+		}
+		n := pkg.Name()
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		names = append(names, n)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("repology: %q has no known package for %s", name, distro)
+	}
+	return names, nil
+}
+
+func hasRepo(pkgs []Package, distro string) bool {
+	for _, pkg := range pkgs {
+		if strings.HasPrefix(pkg.Repo, distro) {
+			return true
+		}
+	}
+	return false
+}
+
+// project fetches (or reuses a cached copy of) a Repology project's package
+// list. Repology reports an unknown project as an empty JSON object ({})
+// rather than an empty array, so that case is treated as "no packages"
+// instead of a decode error.
+func project(name string) ([]Package, error) {
+	uri := fmt.Sprintf("%s/project/%s", apiBaseURI, name)
+
+	body, err := getCached(uri, cacheKey("project", name))
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	if err := json.Unmarshal(body, &pkgs); err != nil {
+		var empty map[string]interface{}
+		if err2 := json.Unmarshal(body, &empty); err2 == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("decoding repology project %q: %v", name, err)
+	}
+	return pkgs, nil
+}
+
+// search queries Repology's project search endpoint and returns the best
+// matching project name, used when the exact name isn't itself a project.
+// If inrepo is non-empty, results are restricted to projects with a package
+// in a repo matching that prefix. The match is picked deterministically: an
+// exact (case-insensitive) name match wins, otherwise the lexicographically
+// first project name is used.
+func search(name, inrepo string) (string, error) {
+	uri := fmt.Sprintf("%s/projects/?search=%s", apiBaseURI, name)
+	if inrepo != "" {
+		uri += "&inrepo=" + inrepo
+	}
+
+	body, err := getCached(uri, cacheKey("search", name+"-"+inrepo))
+	if err != nil {
+		return "", err
+	}
+
+	var results map[string][]Package
+	if err := json.Unmarshal(body, &results); err != nil {
+		return "", fmt.Errorf("decoding repology search for %q: %v", name, err)
+	}
+	if len(results) == 0 {
+		return "", nil
+	}
+
+	projects := make([]string, 0, len(results))
+	for project := range results {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	for _, project := range projects {
+		if strings.EqualFold(project, name) {
+			return project, nil
+		}
+	}
+	return projects[0], nil
+}
+
+// getCached returns the response body for uri, reusing a disk cache entry
+// under key if it is younger than cacheTTL.
+func getCached(uri, key string) ([]byte, error) {
+	cachePath, err := cachePathFor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < cacheTTL {
+		logrus.Debugf("using cached repology response for %s", uri)
+		return ioutil.ReadFile(cachePath)
+	}
+
+	logrus.Debugf("requesting from %s", uri)
+	resp, err := http.Get(uri)
+	if err != nil {
+		return nil, fmt.Errorf("requesting %s: %v", uri, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting %s: unexpected status %s", uri, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(cachePath, body, 0644); err != nil {
+		logrus.Warnf("caching repology response for %s: %v", uri, err)
+	}
+
+	return body, nil
+}
+
+func cacheKey(kind, name string) string {
+	return kind + "-" + strings.Map(func(r rune) rune {
+		if r == '/' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func cachePathFor(key string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(base, "apk-file", "repology")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key+".json"), nil
+}